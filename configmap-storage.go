@@ -32,8 +32,9 @@ type ConfigMapStorageManager struct {
 
 // MapStorage is a configmap as m
 type MapStorage struct {
-	configMap *corev1.ConfigMap
-	lock      *sync.RWMutex
+	configMap  *corev1.ConfigMap
+	lock       *sync.RWMutex
+	lockStopCh chan struct{}
 }
 
 // NewConfigMapStorageManager returns ConfigMapStorageManager
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	lockedLabelKey   = namePrefix + "/locked"
+	lockedByKey      = namePrefix + "/locked-by"
+	lockExpiresAtKey = namePrefix + "/lock-expires-at"
+)
+
+// TryLock attempts to acquire the lock on m once and returns immediately. It
+// succeeds if the lock is free, already expired, or already held by
+// holderID, and fails otherwise without blocking or retrying.
+func (c *ConfigMapStorageManager) TryLock(ctx context.Context, m *MapStorage, holderID string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive, got %s", ttl)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	cm, err := c.k8sclient.CoreV1().ConfigMaps(m.configMap.Namespace).Get(ctx, m.configMap.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	m.configMap = cm
+
+	holder, expiresAt, locked := lockState(m.configMap)
+	if locked && holder != holderID && time.Now().Before(expiresAt) {
+		return fmt.Errorf("MapStorage %s is locked by %s until %s", extractBaseName(m.configMap.Name), holder, expiresAt)
+	}
+
+	setLockState(m.configMap, holderID, time.Now().Add(ttl))
+
+	ret, err := c.k8sclient.CoreV1().ConfigMaps(m.configMap.Namespace).Update(ctx, m.configMap, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	m.configMap = ret
+
+	c.startLockRefresh(ctx, m, holderID, ttl)
+	return nil
+}
+
+// Lock retries TryLock up to attempts times with backoff between attempts. It
+// returns an error if the lock could not be acquired.
+func (c *ConfigMapStorageManager) Lock(ctx context.Context, m *MapStorage, holderID string, ttl time.Duration, attempts int, backoff time.Duration) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		err = c.TryLock(ctx, m, holderID, ttl)
+		if err == nil {
+			return nil
+		}
+		if i == attempts-1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	return fmt.Errorf("failed to lock MapStorage %s after %d attempts: %w", extractBaseName(m.configMap.Name), attempts, err)
+}
+
+// Unlock releases the lock on m, stopping the lease refresh goroutine, but
+// only if it is currently held by holderID.
+func (c *ConfigMapStorageManager) Unlock(ctx context.Context, m *MapStorage, holderID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	cm, err := c.k8sclient.CoreV1().ConfigMaps(m.configMap.Namespace).Get(ctx, m.configMap.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	m.configMap = cm
+
+	holder, _, locked := lockState(m.configMap)
+	if !locked {
+		return nil
+	}
+	if holder != holderID {
+		return fmt.Errorf("MapStorage %s is locked by %s, not %s", extractBaseName(m.configMap.Name), holder, holderID)
+	}
+
+	c.stopLockRefresh(m)
+	clearLockState(m.configMap)
+
+	ret, err := c.k8sclient.CoreV1().ConfigMaps(m.configMap.Namespace).Update(ctx, m.configMap, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	m.configMap = ret
+	return nil
+}
+
+// startLockRefresh starts a background goroutine that periodically extends
+// the lease on m while it is held, stopping when Unlock is called. If ctx is
+// done first, it also stops, best-effort releasing the lock so callers can
+// rely on context cancellation for cleanup/leader step-down instead of
+// having to call Unlock themselves.
+func (c *ConfigMapStorageManager) startLockRefresh(ctx context.Context, m *MapStorage, holderID string, ttl time.Duration) {
+	c.stopLockRefresh(m)
+	stopCh := make(chan struct{})
+	m.lockStopCh = stopCh
+
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ctx.Done():
+				unlockCtx, cancel := context.WithTimeout(context.Background(), ttl)
+				_ = c.Unlock(unlockCtx, m, holderID)
+				cancel()
+				return
+			case <-ticker.C:
+				refreshCtx, cancel := context.WithTimeout(context.Background(), ttl)
+				m.lock.Lock()
+				holder, _, locked := lockState(m.configMap)
+				if locked && holder == holderID {
+					setLockState(m.configMap, holderID, time.Now().Add(ttl))
+					if ret, err := c.k8sclient.CoreV1().ConfigMaps(m.configMap.Namespace).Update(refreshCtx, m.configMap, metav1.UpdateOptions{}); err == nil {
+						m.configMap = ret
+					}
+				}
+				m.lock.Unlock()
+				cancel()
+			}
+		}
+	}()
+}
+
+func (c *ConfigMapStorageManager) stopLockRefresh(m *MapStorage) {
+	if m.lockStopCh != nil {
+		close(m.lockStopCh)
+		m.lockStopCh = nil
+	}
+}
+
+func lockState(cm *corev1.ConfigMap) (holder string, expiresAt time.Time, locked bool) {
+	if cm.Labels[lockedLabelKey] != "true" {
+		return "", time.Time{}, false
+	}
+	holder = cm.Annotations[lockedByKey]
+	expiresAt, _ = time.Parse(time.RFC3339, cm.Annotations[lockExpiresAtKey])
+	return holder, expiresAt, true
+}
+
+func setLockState(cm *corev1.ConfigMap, holderID string, expiresAt time.Time) {
+	if cm.Labels == nil {
+		cm.Labels = map[string]string{}
+	}
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Labels[lockedLabelKey] = "true"
+	cm.Annotations[lockedByKey] = holderID
+	cm.Annotations[lockExpiresAtKey] = expiresAt.Format(time.RFC3339)
+}
+
+func clearLockState(cm *corev1.ConfigMap) {
+	cm.Labels[lockedLabelKey] = "false"
+	delete(cm.Annotations, lockedByKey)
+	delete(cm.Annotations, lockExpiresAtKey)
+}
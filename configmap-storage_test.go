@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"sync"
@@ -12,6 +13,19 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+func TestTryLockRejectsNonPositiveTTL(t *testing.T) {
+	c := &ConfigMapStorageManager{}
+	m := &MapStorage{
+		configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: namePrefix + "." + "foo"}},
+		lock:      new(sync.RWMutex),
+	}
+
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		err := c.TryLock(context.Background(), m, "holder", ttl)
+		assert.Error(t, err)
+	}
+}
+
 func TestExtractBaseName(t *testing.T) {
 	tests := []struct {
 		name   string
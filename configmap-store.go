@@ -2,69 +2,226 @@ package store
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
-	corev1 "k8s.io/api/core/v1"
-	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 const (
 	namePrefix = "store.k8s.jlandowner.com"
+
+	defaultConflictRetries = 3
+	defaultBackoff         = 100 * time.Millisecond
 )
 
+// Option configures a ConfigMapStoreManager.
+type Option func(*storeOptions)
+
+type storeOptions struct {
+	driverFactory   func(*kubernetes.Clientset) Driver
+	encodeValues    bool
+	conflictRetries int
+	backoff         time.Duration
+}
+
+func defaultStoreOptions() *storeOptions {
+	return &storeOptions{
+		driverFactory:   newConfigMapDriver,
+		conflictRetries: defaultConflictRetries,
+		backoff:         defaultBackoff,
+	}
+}
+
+// WithSecretsDriver stores values in corev1.Secret objects instead of the
+// default corev1.ConfigMap, for values that shouldn't live in a ConfigMap.
+func WithSecretsDriver() Option {
+	return func(o *storeOptions) { o.driverFactory = newSecretsDriver }
+}
+
+// WithEncodedValues base64-encodes every value on write and decodes it on
+// read, so binary blobs can be round-tripped safely.
+func WithEncodedValues() Option {
+	return func(o *storeOptions) { o.encodeValues = true }
+}
+
+// WithConflictRetries sets how many times Upsert, Delete and Mutate retry
+// after a 409 Conflict by re-fetching the backing object and re-applying the
+// caller's change on top of its fresh ResourceVersion.
+func WithConflictRetries(n int) Option {
+	return func(o *storeOptions) { o.conflictRetries = n }
+}
+
+// WithBackoff sets the initial delay between conflict retries. The delay
+// doubles after each retry.
+func WithBackoff(d time.Duration) Option {
+	return func(o *storeOptions) { o.backoff = d }
+}
+
+// mapKey identifies a MapStore by the namespace and name of its backing
+// object, so a single manager can watch more than one namespace.
+type mapKey struct {
+	Namespace string
+	Name      string
+}
+
 // ConfigMapStoreManager is a manager of ConfigMaps
 type ConfigMapStoreManager struct {
-	k8sclient *kubernetes.Clientset
-	localMaps map[string]string
-	namespace string
-	lock      *sync.RWMutex
+	driver           Driver
+	localMaps        map[mapKey]string
+	namespaces       []string
+	defaultNamespace string
+	lock             *sync.RWMutex
+	encodeValues     bool
+	conflictRetries  int
+	backoff          time.Duration
+
+	cache        map[mapKey]*DriverObject
+	cacheLock    *sync.RWMutex
+	watchers     map[mapKey][]chan Event
+	watchersLock *sync.Mutex
 }
 
-// MapStore has the ConfigMap and methods to CRUD to the ConfigMap's Data
+// MapStore has the backing object and methods to CRUD its Data
 type MapStore struct {
-	k8sclient *kubernetes.Clientset
-	configMap *corev1.ConfigMap
-	lock      *sync.RWMutex
+	driver          Driver
+	namespace       string
+	obj             *DriverObject
+	lock            *sync.RWMutex
+	lockStopCh      chan struct{}
+	encode          bool
+	conflictRetries int
+	backoff         time.Duration
+	cacheGet        func(key mapKey) (*DriverObject, bool)
+	cacheSet        func(key mapKey, obj *DriverObject)
 }
 
-// NewConfigMapStoreManager returns ConfigMapStoreManager
-func NewConfigMapStoreManager(ctx context.Context, namespace string) (*ConfigMapStoreManager, error) {
+// NewConfigMapStoreManager returns a ConfigMapStoreManager authenticated
+// in-cluster and scoped to a single namespace.
+func NewConfigMapStoreManager(ctx context.Context, namespace string, opts ...Option) (*ConfigMapStoreManager, error) {
 	cfg, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, err
 	}
+	return NewConfigMapStoreManagerForConfig(ctx, cfg, []string{namespace}, opts...)
+}
 
-	client, err := kubernetes.NewForConfig(cfg)
+// NewConfigMapStoreManagerFromKubeconfig returns a ConfigMapStoreManager
+// authenticated from the kubeconfig at path, for CLI tools and controllers
+// running out-of-cluster. contextName selects a non-default context within
+// the kubeconfig; pass "" to use its current-context. namespaces lists
+// every namespace to watch; pass []string{metav1.NamespaceAll} to watch the
+// whole cluster.
+func NewConfigMapStoreManagerFromKubeconfig(ctx context.Context, path, contextName string, namespaces []string, opts ...Option) (*ConfigMapStoreManager, error) {
+	loadingRules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: path}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
+
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
 	if err != nil {
 		return nil, err
 	}
+	return NewConfigMapStoreManagerForConfig(ctx, cfg, namespaces, opts...)
+}
 
-	localmaps := make(map[string]string, 0)
-	maps, err := client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{LabelSelector: getLabelSelector().String()})
+// NewConfigMapStoreManagerForConfig returns a ConfigMapStoreManager built
+// from an explicit *rest.Config, for callers that already have one (e.g.
+// from client-go's own kubeconfig loaders). namespaces lists every namespace
+// to watch; pass []string{metav1.NamespaceAll} to watch the whole cluster.
+// The first entry becomes the default namespace used by NewMapStore,
+// DeleteMapStore, Query and the single-namespace GetMapStore/Watch calls.
+func NewConfigMapStoreManagerForConfig(ctx context.Context, cfg *rest.Config, namespaces []string, opts ...Option) (*ConfigMapStoreManager, error) {
+	client, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
-	for _, cm := range maps.Items {
-		localmaps[extractBaseName(cm.Name)] = cm.Name
+
+	o := defaultStoreOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	driver := o.driverFactory(client)
+
+	localmaps := make(map[mapKey]string, 0)
+	objCache := make(map[mapKey]*DriverObject, 0)
+	for _, ns := range namespaces {
+		objs, err := driver.List(ctx, ns, notAShardSelector)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			key := mapKey{Namespace: obj.Namespace, Name: extractBaseName(obj.Name)}
+			localmaps[key] = obj.Name
+			objCache[key] = obj
+		}
+	}
+
+	c := &ConfigMapStoreManager{
+		driver:           driver,
+		localMaps:        localmaps,
+		lock:             new(sync.RWMutex),
+		namespaces:       namespaces,
+		defaultNamespace: namespaces[0],
+		encodeValues:     o.encodeValues,
+		conflictRetries:  o.conflictRetries,
+		backoff:          o.backoff,
+		cache:            objCache,
+		cacheLock:        new(sync.RWMutex),
+		watchers:         make(map[mapKey][]chan Event),
+		watchersLock:     new(sync.Mutex),
+	}
+
+	for _, ns := range namespaces {
+		if err := driver.Watch(ctx, ns, notAShardSelector, c.onAdd, c.onUpdate, c.onDelete); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// ListNamespaces returns every namespace this manager watches. If it was
+// constructed with metav1.NamespaceAll, it instead returns every namespace
+// currently holding at least one MapStore.
+func (c *ConfigMapStoreManager) ListNamespaces() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if !(len(c.namespaces) == 1 && c.namespaces[0] == metav1.NamespaceAll) {
+		namespaces := make([]string, len(c.namespaces))
+		copy(namespaces, c.namespaces)
+		return namespaces
 	}
 
-	return &ConfigMapStoreManager{
-		k8sclient: client,
-		localMaps: localmaps,
-		lock:      new(sync.RWMutex),
-		namespace: namespace,
-	}, nil
+	seen := make(map[string]struct{})
+	for key := range c.localMaps {
+		seen[key.Namespace] = struct{}{}
+	}
+	namespaces := make([]string, 0, len(seen))
+	for ns := range seen {
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces
 }
 
-// NewMapStore creates new ConfigMap as store and returns MapStore
+// NewMapStore creates a new backing object, in the manager's default
+// namespace, as a store and returns MapStore.
 func (c *ConfigMapStoreManager) NewMapStore(ctx context.Context, name string) (*MapStore, error) {
-	_, exist := c.localMaps[name]
+	key := mapKey{Namespace: c.defaultNamespace, Name: name}
+	c.lock.RLock()
+	_, exist := c.localMaps[key]
+	c.lock.RUnlock()
 	if exist {
 		return c.GetMapStore(ctx, name)
 	}
@@ -72,138 +229,194 @@ func (c *ConfigMapStoreManager) NewMapStore(ctx context.Context, name string) (*
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	cm := &corev1.ConfigMap{}
-	cm.SetName(namePrefix + "." + name)
-	cm.SetLabels(getLabels())
-
-	if c.k8sclient != nil {
-		ret, err := c.k8sclient.CoreV1().ConfigMaps(c.namespace).Create(ctx, cm, metav1.CreateOptions{})
-		if err != nil && !apierrs.IsAlreadyExists(err) {
+	obj := &DriverObject{Name: namePrefix + "." + name, Namespace: c.defaultNamespace}
+	if c.driver != nil {
+		ret, err := c.driver.Create(ctx, c.defaultNamespace, name, nil)
+		if err != nil {
 			return nil, err
 		}
-		cm = ret
+		obj = ret
 	}
-	c.localMaps[name] = cm.Name
-	return &MapStore{k8sclient: c.k8sclient, configMap: cm, lock: new(sync.RWMutex)}, nil
+
+	c.localMaps[key] = obj.Name
+	c.cacheStore(key, obj)
+	return c.newMapStore(obj.DeepCopy()), nil
 }
 
-// DeleteMapStore removes ConfigMap
+// DeleteMapStore removes the backing object from the manager's default
+// namespace.
 func (c *ConfigMapStoreManager) DeleteMapStore(ctx context.Context, name string) error {
-	cname, exist := c.localMaps[name]
+	key := mapKey{Namespace: c.defaultNamespace, Name: name}
+	c.lock.RLock()
+	objName, exist := c.localMaps[key]
+	c.lock.RUnlock()
 	if !exist {
 		return fmt.Errorf("MapStore %s do not exist in cluster", name)
 	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	if c.k8sclient != nil {
-		err := c.k8sclient.CoreV1().ConfigMaps(c.namespace).Delete(ctx, cname, metav1.DeleteOptions{})
-		if err != nil {
+	if c.driver != nil {
+		if err := c.driver.Delete(ctx, c.defaultNamespace, objName); err != nil {
 			return err
 		}
 	}
 
-	delete(c.localMaps, name)
+	delete(c.localMaps, key)
+	c.cacheDelete(key)
 	return nil
 }
 
-// GetMapStore returns MapStore by given name
-func (c *ConfigMapStoreManager) GetMapStore(ctx context.Context, name string) (*MapStore, error) {
-	cname, exist := c.localMaps[name]
+// GetMapStore returns the MapStore named name in the manager's default
+// namespace. By default it is served from the informer-backed cache; pass
+// WithLiveRead() to force a read straight from the API server.
+func (c *ConfigMapStoreManager) GetMapStore(ctx context.Context, name string, opts ...GetOption) (*MapStore, error) {
+	return c.GetMapStoreInNamespace(ctx, c.defaultNamespace, name, opts...)
+}
+
+// GetMapStoreInNamespace returns the MapStore named name in namespace. Pass
+// "" for namespace to use the manager's default namespace. By default it is
+// served from the informer-backed cache; pass WithLiveRead() to force a read
+// straight from the API server.
+func (c *ConfigMapStoreManager) GetMapStoreInNamespace(ctx context.Context, namespace, name string, opts ...GetOption) (*MapStore, error) {
+	if namespace == "" {
+		namespace = c.defaultNamespace
+	}
+
+	key := mapKey{Namespace: namespace, Name: name}
+	c.lock.RLock()
+	objName, exist := c.localMaps[key]
+	c.lock.RUnlock()
 	if !exist {
 		return nil, fmt.Errorf("MapStore %s do not exist in cluster", name)
 	}
 
-	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: cname, Namespace: c.namespace}}
-	if c.k8sclient != nil {
-		ret, err := c.k8sclient.CoreV1().ConfigMaps(c.namespace).Get(ctx, cname, metav1.GetOptions{})
+	o := &getOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if !o.live {
+		if cached, ok := c.cacheLookup(key); ok {
+			// Deep-copy: the cache may hand the same object to another
+			// caller (or keep serving it to future Gets) concurrently with
+			// this MapStore's in-place Mutate edits.
+			return c.newMapStore(cached.DeepCopy()), nil
+		}
+	}
+
+	obj := &DriverObject{Name: objName, Namespace: namespace}
+	if c.driver != nil {
+		ret, err := c.driver.Get(ctx, namespace, objName)
 		if err != nil {
 			return nil, err
 		}
-		cm = ret
+		obj = ret
 	}
 
-	return &MapStore{k8sclient: c.k8sclient, configMap: cm, lock: new(sync.RWMutex)}, nil
+	return c.newMapStore(obj), nil
 }
 
-// Upsert update or insert value by given key
-func (m *MapStore) Upsert(ctx context.Context, key, value string) error {
-	m.lock.Lock()
-	defer m.lock.Unlock()
+// Query returns every MapStore in the manager's default namespace whose
+// backing object labels match lbls. Shard fragments are never returned, the
+// same as the manager's own construction-time List/Watch.
+func (c *ConfigMapStoreManager) Query(ctx context.Context, lbls map[string]string) ([]*MapStore, error) {
+	if c.driver == nil {
+		return nil, fmt.Errorf("Query requires a driver")
+	}
+
+	reqs, _ := notAShardSelector.Requirements()
+	selector := labels.SelectorFromSet(lbls).Add(reqs...)
 
-	if m.configMap.Data != nil {
-		m.configMap.Data[key] = value
-	} else {
-		m.configMap.Data = map[string]string{key: value}
+	objs, err := c.driver.List(ctx, c.defaultNamespace, selector)
+	if err != nil {
+		return nil, err
 	}
 
-	if m.k8sclient != nil {
-		ret, err := m.k8sclient.CoreV1().ConfigMaps(m.configMap.Namespace).Update(ctx, m.configMap, metav1.UpdateOptions{})
-		if err != nil {
-			return err
-		}
-		m.configMap = ret
+	stores := make([]*MapStore, 0, len(objs))
+	for _, obj := range objs {
+		stores = append(stores, c.newMapStore(obj))
+	}
+	return stores, nil
+}
+
+func (c *ConfigMapStoreManager) newMapStore(obj *DriverObject) *MapStore {
+	namespace := obj.Namespace
+	if namespace == "" {
+		namespace = c.defaultNamespace
+	}
+	return &MapStore{
+		driver:          c.driver,
+		namespace:       namespace,
+		obj:             obj,
+		lock:            new(sync.RWMutex),
+		encode:          c.encodeValues,
+		conflictRetries: c.conflictRetries,
+		backoff:         c.backoff,
+		cacheGet:        c.cacheLookup,
+		cacheSet:        c.cacheStore,
 	}
-	return nil
 }
 
-// Delete remove the given key
-func (m *MapStore) Delete(ctx context.Context, key string) error {
+// Get returns value by given key. By default it is served from the
+// informer-backed cache, falling back to a live read on a cache miss; pass
+// WithLiveRead() to always force a read straight from the API server.
+func (m *MapStore) Get(ctx context.Context, key string, opts ...GetOption) (string, error) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	if m.configMap.Data != nil {
-		return fmt.Errorf("MapStore %s does not have key %s", extractBaseName(m.configMap.Name), key)
+	o := &getOptions{}
+	for _, opt := range opts {
+		opt(o)
 	}
-	if _, exist := m.configMap.Data[key]; !exist {
-		return fmt.Errorf("MapStore %s does not have key %s", extractBaseName(m.configMap.Name), key)
-	}
-
-	delete(m.configMap.Data, key)
 
-	if m.k8sclient != nil {
-		ret, err := m.k8sclient.CoreV1().ConfigMaps(m.configMap.Namespace).Update(ctx, m.configMap, metav1.UpdateOptions{})
-		if err != nil {
-			return err
+	cacheHit := false
+	if !o.live && m.cacheGet != nil {
+		cacheKey := mapKey{Namespace: m.namespace, Name: extractBaseName(m.obj.Name)}
+		if obj, ok := m.cacheGet(cacheKey); ok {
+			m.obj = obj
+			cacheHit = true
 		}
-		m.configMap = ret
 	}
-	return nil
-}
-
-// Get returns value by given key
-func (m *MapStore) Get(ctx context.Context, key string) (string, error) {
-	if m.k8sclient != nil {
-		cm, err := m.k8sclient.CoreV1().ConfigMaps(m.configMap.Namespace).Get(ctx, m.configMap.Name, metav1.GetOptions{})
+	// A miss here isn't necessarily staleness in o.live's sense: the object may
+	// simply never be cached (e.g. a ShardedMapStore's shards are excluded from
+	// the informer cache by notAShardSelector), so always fall back to a live
+	// read rather than silently serving whatever m.obj already held.
+	if !cacheHit && m.driver != nil {
+		obj, err := m.driver.Get(ctx, m.namespace, m.obj.Name)
 		if err != nil {
 			return "", err
 		}
-		m.configMap = cm
+		m.obj = obj
 	}
-	if m.configMap.Data == nil {
-		return "", fmt.Errorf("MapStore %s does not have key %s", extractBaseName(m.configMap.Name), key)
+	if m.obj.Data == nil {
+		return "", fmt.Errorf("MapStore %s does not have key %s", extractBaseName(m.obj.Name), key)
 	}
-	val, exist := m.configMap.Data[key]
+	val, exist := m.obj.Data[key]
 	if !exist {
-		return "", fmt.Errorf("MapStore %s does not have key %s", extractBaseName(m.configMap.Name), key)
+		return "", fmt.Errorf("MapStore %s does not have key %s", extractBaseName(m.obj.Name), key)
 	}
 
-	return val, nil
+	return m.decode(val)
 }
 
-// GetConfigMap returns corev1.ConfigMap of MapStore
-func (m *MapStore) GetConfigMap() corev1.ConfigMap {
-	return *m.configMap
+// decode reverses the base64 encoding Mutate applies to stored values when
+// m.encode is set, leaving val untouched otherwise.
+func (m *MapStore) decode(val string) (string, error) {
+	return decodeValue(val, m.encode)
 }
 
-func getLabelSelector() labels.Selector {
-	labelSelector, _ := labels.Parse(namePrefix + "/store in (1)")
-	return labelSelector
-}
-
-func getLabels() map[string]string {
-	labels := map[string]string{namePrefix + "/store": "1"}
-	return labels
+// decodeValue reverses the base64 encoding Mutate applies to a stored value
+// when encode is set, leaving val untouched otherwise.
+func decodeValue(val string, encode bool) (string, error) {
+	if !encode {
+		return val, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(val)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
 }
 
 func extractBaseName(name string) string {
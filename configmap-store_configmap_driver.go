@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const configMapDriverSelector = namePrefix + "/store in (1)"
+
+// configMapDriver is the default Driver, backed by corev1.ConfigMap.
+type configMapDriver struct {
+	k8sclient *kubernetes.Clientset
+}
+
+func newConfigMapDriver(client *kubernetes.Clientset) Driver {
+	return &configMapDriver{k8sclient: client}
+}
+
+func (d *configMapDriver) Create(ctx context.Context, namespace, name string, lbls map[string]string) (*DriverObject, error) {
+	cm := &corev1.ConfigMap{}
+	cm.SetName(namePrefix + "." + name)
+	cm.SetLabels(mergeLabels(getLabels(), lbls))
+
+	ret, err := d.k8sclient.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+	if apierrs.IsAlreadyExists(err) {
+		return d.Get(ctx, namespace, cm.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromConfigMap(ret), nil
+}
+
+func (d *configMapDriver) Get(ctx context.Context, namespace, name string) (*DriverObject, error) {
+	ret, err := d.k8sclient.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return fromConfigMap(ret), nil
+}
+
+func (d *configMapDriver) Update(ctx context.Context, namespace string, obj *DriverObject) (*DriverObject, error) {
+	ret, err := d.k8sclient.CoreV1().ConfigMaps(namespace).Update(ctx, toConfigMap(namespace, obj), metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return fromConfigMap(ret), nil
+}
+
+func (d *configMapDriver) Delete(ctx context.Context, namespace, name string) error {
+	return d.k8sclient.CoreV1().ConfigMaps(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (d *configMapDriver) List(ctx context.Context, namespace string, selector labels.Selector) ([]*DriverObject, error) {
+	combined, err := combineSelector(selector, configMapDriverSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := d.k8sclient.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{LabelSelector: combined.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]*DriverObject, 0, len(ret.Items))
+	for i := range ret.Items {
+		objs = append(objs, fromConfigMap(&ret.Items[i]))
+	}
+	return objs, nil
+}
+
+func (d *configMapDriver) Watch(ctx context.Context, namespace string, selector labels.Selector, onAdd, onUpdate func(*DriverObject), onDelete func(namespace, name string)) error {
+	combined, err := combineSelector(selector, configMapDriverSelector)
+	if err != nil {
+		return err
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(d.k8sclient, time.Minute,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = combined.String()
+		}),
+	)
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if cm, ok := obj.(*corev1.ConfigMap); ok {
+				onAdd(fromConfigMap(cm))
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if cm, ok := newObj.(*corev1.ConfigMap); ok {
+				onUpdate(fromConfigMap(cm))
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if cm, ok := obj.(*corev1.ConfigMap); ok {
+				onDelete(cm.Namespace, cm.Name)
+				return
+			}
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				if cm, ok := tombstone.Obj.(*corev1.ConfigMap); ok {
+					onDelete(cm.Namespace, cm.Name)
+				}
+			}
+		},
+	})
+
+	go informer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to sync ConfigMap informer cache")
+	}
+	return nil
+}
+
+func fromConfigMap(cm *corev1.ConfigMap) *DriverObject {
+	return &DriverObject{
+		Name:            cm.Name,
+		Namespace:       cm.Namespace,
+		Labels:          cm.Labels,
+		Annotations:     cm.Annotations,
+		Data:            cm.Data,
+		ResourceVersion: cm.ResourceVersion,
+	}
+}
+
+func toConfigMap(namespace string, obj *DriverObject) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{}
+	cm.SetName(obj.Name)
+	cm.SetNamespace(namespace)
+	cm.SetLabels(obj.Labels)
+	cm.SetAnnotations(obj.Annotations)
+	cm.ResourceVersion = obj.ResourceVersion
+	cm.Data = obj.Data
+	return cm
+}
+
+func getLabels() map[string]string {
+	return map[string]string{namePrefix + "/store": "1"}
+}
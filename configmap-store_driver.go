@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DriverObject is the backend-agnostic representation of a MapStore's
+// backing Kubernetes object (a ConfigMap or a Secret), as returned by a
+// Driver.
+type DriverObject struct {
+	Name            string
+	Namespace       string
+	Labels          map[string]string
+	Annotations     map[string]string
+	Data            map[string]string
+	ResourceVersion string
+}
+
+// DeepCopy returns obj with its own independent Labels, Annotations and Data
+// maps, so a caller can hand out a cached *DriverObject without letting the
+// recipient's in-place edits (e.g. MapStore.Mutate) reach back into the
+// cache or any other MapStore built from the same cache entry.
+func (obj *DriverObject) DeepCopy() *DriverObject {
+	if obj == nil {
+		return nil
+	}
+	cp := *obj
+	cp.Labels = copyStringMap(obj.Labels)
+	cp.Annotations = copyStringMap(obj.Annotations)
+	cp.Data = copyStringMap(obj.Data)
+	return &cp
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	cp := make(map[string]string, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return cp
+}
+
+// Driver persists MapStore data to a Kubernetes backend, letting
+// ConfigMapStoreManager swap backends (a corev1.ConfigMap, a corev1.Secret,
+// ...) behind a single CRUD surface.
+type Driver interface {
+	// Create creates a new backing object named name in namespace with lbls
+	// merged into the driver's own labels, or returns the existing object if
+	// one is already present.
+	Create(ctx context.Context, namespace, name string, lbls map[string]string) (*DriverObject, error)
+	// Get fetches the current state of the named backing object.
+	Get(ctx context.Context, namespace, name string) (*DriverObject, error)
+	// Update persists obj, using its ResourceVersion for optimistic
+	// concurrency.
+	Update(ctx context.Context, namespace string, obj *DriverObject) (*DriverObject, error)
+	// Delete removes the named backing object.
+	Delete(ctx context.Context, namespace, name string) error
+	// List returns every backing object managed by this driver in namespace
+	// whose labels additionally match selector.
+	List(ctx context.Context, namespace string, selector labels.Selector) ([]*DriverObject, error)
+	// Watch starts an informer over this driver's backing resource type,
+	// scoped to namespace and selector, invoking onAdd/onUpdate with the
+	// converted object and onDelete with the object's namespace and its full
+	// (prefixed) name. It blocks until the informer's cache has synced, and
+	// runs until ctx is done.
+	Watch(ctx context.Context, namespace string, selector labels.Selector, onAdd, onUpdate func(*DriverObject), onDelete func(namespace, name string)) error
+}
+
+func mergeLabels(base, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(extra))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+// combineSelector ANDs a driver's own discovery selector onto a caller's
+// selector so List calls only ever see objects the driver manages.
+func combineSelector(selector labels.Selector, own string) (labels.Selector, error) {
+	ownSelector, err := labels.Parse(own)
+	if err != nil {
+		return nil, err
+	}
+	reqs, _ := ownSelector.Requirements()
+	return selector.Add(reqs...), nil
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	lockedLabelKey   = namePrefix + "/locked"
+	lockedByKey      = namePrefix + "/locked-by"
+	lockExpiresAtKey = namePrefix + "/lock-expires-at"
+)
+
+// TryLock attempts to acquire the lock on the MapStore once and returns
+// immediately. It succeeds if the lock is free, already expired, or already
+// held by holderID, and fails otherwise without blocking or retrying.
+func (m *MapStore) TryLock(ctx context.Context, holderID string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive, got %s", ttl)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.driver != nil {
+		obj, err := m.driver.Get(ctx, m.namespace, m.obj.Name)
+		if err != nil {
+			return err
+		}
+		m.obj = obj
+	}
+
+	holder, expiresAt, locked := m.lockState()
+	if locked && holder != holderID && time.Now().Before(expiresAt) {
+		return fmt.Errorf("MapStore %s is locked by %s until %s", extractBaseName(m.obj.Name), holder, expiresAt)
+	}
+
+	m.setLockState(holderID, time.Now().Add(ttl))
+
+	if m.driver != nil {
+		ret, err := m.driver.Update(ctx, m.namespace, m.obj)
+		if err != nil {
+			return err
+		}
+		m.obj = ret
+	}
+
+	m.startLockRefresh(ctx, holderID, ttl)
+	return nil
+}
+
+// Lock retries TryLock up to attempts times with backoff between attempts,
+// retrying on conflicting updates as well as on a lock that is still held by
+// another holder. It returns an error if the lock could not be acquired.
+func (m *MapStore) Lock(ctx context.Context, holderID string, ttl time.Duration, attempts int, backoff time.Duration) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		err = m.TryLock(ctx, holderID, ttl)
+		if err == nil {
+			return nil
+		}
+		if !apierrs.IsConflict(err) && i == attempts-1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	return fmt.Errorf("failed to lock MapStore %s after %d attempts: %w", extractBaseName(m.obj.Name), attempts, err)
+}
+
+// Unlock releases the lock, stopping the lease refresh goroutine, but only if
+// it is currently held by holderID.
+func (m *MapStore) Unlock(ctx context.Context, holderID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.driver != nil {
+		obj, err := m.driver.Get(ctx, m.namespace, m.obj.Name)
+		if err != nil {
+			return err
+		}
+		m.obj = obj
+	}
+
+	holder, _, locked := m.lockState()
+	if !locked {
+		return nil
+	}
+	if holder != holderID {
+		return fmt.Errorf("MapStore %s is locked by %s, not %s", extractBaseName(m.obj.Name), holder, holderID)
+	}
+
+	m.stopLockRefresh()
+	m.clearLockState()
+
+	if m.driver != nil {
+		ret, err := m.driver.Update(ctx, m.namespace, m.obj)
+		if err != nil {
+			return err
+		}
+		m.obj = ret
+	}
+	return nil
+}
+
+// startLockRefresh starts a background goroutine that periodically extends
+// the lease while it is held, stopping when Unlock is called. If ctx is done
+// first, it also stops, best-effort releasing the lock so callers can rely
+// on context cancellation for cleanup/leader step-down instead of having to
+// call Unlock themselves.
+func (m *MapStore) startLockRefresh(ctx context.Context, holderID string, ttl time.Duration) {
+	m.stopLockRefresh()
+	stopCh := make(chan struct{})
+	m.lockStopCh = stopCh
+
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ctx.Done():
+				unlockCtx, cancel := context.WithTimeout(context.Background(), ttl)
+				_ = m.Unlock(unlockCtx, holderID)
+				cancel()
+				return
+			case <-ticker.C:
+				refreshCtx, cancel := context.WithTimeout(context.Background(), ttl)
+				m.lock.Lock()
+				holder, _, locked := m.lockState()
+				if locked && holder == holderID {
+					m.setLockState(holderID, time.Now().Add(ttl))
+					if m.driver != nil {
+						if ret, err := m.driver.Update(refreshCtx, m.namespace, m.obj); err == nil {
+							m.obj = ret
+						}
+					}
+				}
+				m.lock.Unlock()
+				cancel()
+			}
+		}
+	}()
+}
+
+func (m *MapStore) stopLockRefresh() {
+	if m.lockStopCh != nil {
+		close(m.lockStopCh)
+		m.lockStopCh = nil
+	}
+}
+
+func (m *MapStore) lockState() (holder string, expiresAt time.Time, locked bool) {
+	if m.obj.Labels[lockedLabelKey] != "true" {
+		return "", time.Time{}, false
+	}
+	holder = m.obj.Annotations[lockedByKey]
+	expiresAt, _ = time.Parse(time.RFC3339, m.obj.Annotations[lockExpiresAtKey])
+	return holder, expiresAt, true
+}
+
+func (m *MapStore) setLockState(holderID string, expiresAt time.Time) {
+	if m.obj.Labels == nil {
+		m.obj.Labels = map[string]string{}
+	}
+	if m.obj.Annotations == nil {
+		m.obj.Annotations = map[string]string{}
+	}
+	m.obj.Labels[lockedLabelKey] = "true"
+	m.obj.Annotations[lockedByKey] = holderID
+	m.obj.Annotations[lockExpiresAtKey] = expiresAt.Format(time.RFC3339)
+}
+
+func (m *MapStore) clearLockState() {
+	m.obj.Labels[lockedLabelKey] = "false"
+	delete(m.obj.Annotations, lockedByKey)
+	delete(m.obj.Annotations, lockExpiresAtKey)
+}
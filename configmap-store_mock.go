@@ -7,11 +7,18 @@ import (
 
 // NewMockConfigMapStoreManager returns mock of ConfigMapStoreManager
 func NewMockConfigMapStoreManager(ctx context.Context, namespace string) (*ConfigMapStoreManager, error) {
-	localmaps := make(map[string]string, 0)
+	localmaps := make(map[mapKey]string, 0)
 	return &ConfigMapStoreManager{
-		k8sclient: nil,
-		localMaps: localmaps,
-		lock:      new(sync.RWMutex),
-		namespace: namespace,
+		driver:           nil,
+		localMaps:        localmaps,
+		lock:             new(sync.RWMutex),
+		namespaces:       []string{namespace},
+		defaultNamespace: namespace,
+		conflictRetries:  defaultConflictRetries,
+		backoff:          defaultBackoff,
+		cache:            make(map[mapKey]*DriverObject, 0),
+		cacheLock:        new(sync.RWMutex),
+		watchers:         make(map[mapKey][]chan Event),
+		watchersLock:     new(sync.Mutex),
 	}, nil
 }
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Upsert update or insert value by given key
+func (m *MapStore) Upsert(ctx context.Context, key, value string) error {
+	return m.Mutate(ctx, key, func(string, bool) (string, bool, error) {
+		return value, false, nil
+	})
+}
+
+// Delete remove the given key
+func (m *MapStore) Delete(ctx context.Context, key string) error {
+	return m.Mutate(ctx, key, func(_ string, existed bool) (string, bool, error) {
+		if !existed {
+			return "", false, fmt.Errorf("MapStore %s does not have key %s", extractBaseName(m.obj.Name), key)
+		}
+		return "", true, nil
+	})
+}
+
+// Mutate atomically reads the current value for key (oldVal is "" and
+// existed is false if it is unset), applies fn to compute the new value, and
+// persists it. It builds the new Data on a copy and only swaps it into m.obj
+// once the write succeeds, so a non-conflict error never leaves m.obj (or the
+// cache) reflecting a change that was never actually persisted. On a 409
+// Conflict it re-fetches the live object and retries fn on top of the fresh
+// ResourceVersion, up to the manager's configured WithConflictRetries,
+// backing off by WithBackoff between attempts.
+func (m *MapStore) Mutate(ctx context.Context, key string, fn func(oldVal string, existed bool) (newVal string, del bool, err error)) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.withConflictRetry(ctx, func() error {
+		oldVal, existed, err := m.rawValue(key)
+		if err != nil {
+			return err
+		}
+
+		newVal, del, err := fn(oldVal, existed)
+		if err != nil {
+			return err
+		}
+
+		data := copyStringMap(m.obj.Data)
+		if del {
+			if !existed {
+				return nil
+			}
+			delete(data, key)
+		} else {
+			if m.encode {
+				newVal = base64.StdEncoding.EncodeToString([]byte(newVal))
+			}
+			if data == nil {
+				data = map[string]string{key: newVal}
+			} else {
+				data[key] = newVal
+			}
+		}
+
+		if m.driver == nil {
+			m.obj.Data = data
+			return nil
+		}
+
+		candidate := *m.obj
+		candidate.Data = data
+		ret, err := m.driver.Update(ctx, m.namespace, &candidate)
+		if err != nil {
+			return err
+		}
+		m.obj = ret
+		if m.cacheSet != nil {
+			m.cacheSet(mapKey{Namespace: m.namespace, Name: extractBaseName(m.obj.Name)}, m.obj)
+		}
+		return nil
+	})
+}
+
+// rawValue returns the stored (decoded) value for key without refreshing
+// from the driver, so callers can apply it on top of the in-memory object.
+func (m *MapStore) rawValue(key string) (string, bool, error) {
+	val, exist := m.obj.Data[key]
+	if !exist {
+		return "", false, nil
+	}
+	if !m.encode {
+		return val, true, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(val)
+	if err != nil {
+		return "", true, err
+	}
+	return string(raw), true, nil
+}
+
+// withConflictRetry runs op, and on apierrs.IsConflict(err) re-fetches the
+// live object before retrying op, backing off exponentially between
+// attempts.
+func (m *MapStore) withConflictRetry(ctx context.Context, op func() error) error {
+	backoff := m.backoff
+	var err error
+	for attempt := 0; attempt <= m.conflictRetries; attempt++ {
+		err = op()
+		if err == nil || !apierrs.IsConflict(err) {
+			return err
+		}
+		if attempt == m.conflictRetries {
+			break
+		}
+
+		if m.driver != nil {
+			obj, gerr := m.driver.Get(ctx, m.namespace, m.obj.Name)
+			if gerr != nil {
+				return gerr
+			}
+			m.obj = obj
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const secretDriverSelector = namePrefix + "/secret-store in (1)"
+
+// secretsDriver is a Driver backed by corev1.Secret, for values that
+// shouldn't live in a plaintext ConfigMap.
+type secretsDriver struct {
+	k8sclient *kubernetes.Clientset
+}
+
+func newSecretsDriver(client *kubernetes.Clientset) Driver {
+	return &secretsDriver{k8sclient: client}
+}
+
+func (d *secretsDriver) Create(ctx context.Context, namespace, name string, lbls map[string]string) (*DriverObject, error) {
+	secret := &corev1.Secret{}
+	secret.SetName(namePrefix + "." + name)
+	secret.SetLabels(mergeLabels(getSecretLabels(), lbls))
+
+	ret, err := d.k8sclient.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if apierrs.IsAlreadyExists(err) {
+		return d.Get(ctx, namespace, secret.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromSecret(ret), nil
+}
+
+func (d *secretsDriver) Get(ctx context.Context, namespace, name string) (*DriverObject, error) {
+	ret, err := d.k8sclient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return fromSecret(ret), nil
+}
+
+func (d *secretsDriver) Update(ctx context.Context, namespace string, obj *DriverObject) (*DriverObject, error) {
+	ret, err := d.k8sclient.CoreV1().Secrets(namespace).Update(ctx, toSecret(namespace, obj), metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return fromSecret(ret), nil
+}
+
+func (d *secretsDriver) Delete(ctx context.Context, namespace, name string) error {
+	return d.k8sclient.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (d *secretsDriver) List(ctx context.Context, namespace string, selector labels.Selector) ([]*DriverObject, error) {
+	combined, err := combineSelector(selector, secretDriverSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := d.k8sclient.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: combined.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]*DriverObject, 0, len(ret.Items))
+	for i := range ret.Items {
+		objs = append(objs, fromSecret(&ret.Items[i]))
+	}
+	return objs, nil
+}
+
+func (d *secretsDriver) Watch(ctx context.Context, namespace string, selector labels.Selector, onAdd, onUpdate func(*DriverObject), onDelete func(namespace, name string)) error {
+	combined, err := combineSelector(selector, secretDriverSelector)
+	if err != nil {
+		return err
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(d.k8sclient, time.Minute,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = combined.String()
+		}),
+	)
+	informer := factory.Core().V1().Secrets().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if secret, ok := obj.(*corev1.Secret); ok {
+				onAdd(fromSecret(secret))
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if secret, ok := newObj.(*corev1.Secret); ok {
+				onUpdate(fromSecret(secret))
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if secret, ok := obj.(*corev1.Secret); ok {
+				onDelete(secret.Namespace, secret.Name)
+				return
+			}
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				if secret, ok := tombstone.Obj.(*corev1.Secret); ok {
+					onDelete(secret.Namespace, secret.Name)
+				}
+			}
+		},
+	})
+
+	go informer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to sync Secret informer cache")
+	}
+	return nil
+}
+
+func getSecretLabels() map[string]string {
+	return map[string]string{namePrefix + "/secret-store": "1"}
+}
+
+func fromSecret(secret *corev1.Secret) *DriverObject {
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	return &DriverObject{
+		Name:            secret.Name,
+		Namespace:       secret.Namespace,
+		Labels:          secret.Labels,
+		Annotations:     secret.Annotations,
+		Data:            data,
+		ResourceVersion: secret.ResourceVersion,
+	}
+}
+
+func toSecret(namespace string, obj *DriverObject) *corev1.Secret {
+	secret := &corev1.Secret{}
+	secret.SetName(obj.Name)
+	secret.SetNamespace(namespace)
+	secret.SetLabels(obj.Labels)
+	secret.SetAnnotations(obj.Annotations)
+	secret.ResourceVersion = obj.ResourceVersion
+
+	data := make(map[string][]byte, len(obj.Data))
+	for k, v := range obj.Data {
+		data[k] = []byte(v)
+	}
+	secret.Data = data
+	return secret
+}
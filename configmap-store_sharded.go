@@ -0,0 +1,350 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+const (
+	// shardByteBudget is the per-shard size budget that triggers a split,
+	// kept comfortably under the ~1 MiB ConfigMap/Secret size limit.
+	shardByteBudget = 900 * 1024
+
+	shardOfLabelKey    = namePrefix + "/shard-of"
+	shardIndexLabelKey = namePrefix + "/shard-index"
+	shardCountLabelKey = namePrefix + "/shard-count"
+)
+
+// notAShardSelector matches only backing objects that are not a shard, so
+// the manager's top-level List/Watch never pulls a shard's "<name>.<idx>"
+// object into its own name-keyed localMaps/cache (extractBaseName would
+// otherwise keep the shard index, colliding across every logical name).
+var notAShardSelector = func() labels.Selector {
+	req, err := labels.NewRequirement(shardOfLabelKey, selection.DoesNotExist, nil)
+	if err != nil {
+		panic(err)
+	}
+	return labels.NewSelector().Add(*req)
+}()
+
+// ShardedMapStore transparently spreads a single logical key space across N
+// underlying MapStores (named "<namePrefix>.<name>.<shardIndex>"), so it can
+// exceed the ~1 MiB size limit of a single ConfigMap or Secret.
+type ShardedMapStore struct {
+	manager    *ConfigMapStoreManager
+	name       string
+	lock       *sync.RWMutex
+	shards     map[int]*MapStore
+	shardCount int
+}
+
+// NewShardedMapStore discovers the existing shards of name, by the
+// store.k8s.jlandowner.com/shard-of label, or starts with none; shards are
+// created lazily on first write.
+func (c *ConfigMapStoreManager) NewShardedMapStore(ctx context.Context, name string) (*ShardedMapStore, error) {
+	shards := make(map[int]*MapStore)
+	shardCount := 0
+
+	if c.driver != nil {
+		objs, err := c.driver.List(ctx, c.defaultNamespace, labels.SelectorFromSet(map[string]string{shardOfLabelKey: name}))
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			idx, err := shardIndexOf(obj)
+			if err != nil {
+				continue
+			}
+			shards[idx] = c.newMapStore(obj)
+			// shardCountLabelKey is the authoritative N every shard was
+			// written with; a lazily-never-created top shard must not
+			// shrink it back to max(idx)+1, or keys hashed with the real N
+			// would misroute to the wrong (and wrong-sized) modulus.
+			if count, err := strconv.Atoi(obj.Labels[shardCountLabelKey]); err == nil && count > shardCount {
+				shardCount = count
+			} else if idx+1 > shardCount {
+				shardCount = idx + 1
+			}
+		}
+	}
+	if shardCount == 0 {
+		shardCount = 1
+	}
+
+	return &ShardedMapStore{
+		manager:    c,
+		name:       name,
+		lock:       new(sync.RWMutex),
+		shards:     shards,
+		shardCount: shardCount,
+	}, nil
+}
+
+// Upsert update or insert value by given key, routing it to its shard.
+func (s *ShardedMapStore) Upsert(ctx context.Context, key, value string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	shard, err := s.shardFor(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := shard.Upsert(ctx, key, value); err != nil {
+		return err
+	}
+
+	return s.maybeSplit(ctx, shard)
+}
+
+// Delete removes the given key from its shard.
+func (s *ShardedMapStore) Delete(ctx context.Context, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	idx := shardIndex(key, s.shardCount)
+	shard, exist := s.shards[idx]
+	if !exist {
+		return fmt.Errorf("ShardedMapStore %s does not have key %s", s.name, key)
+	}
+	return shard.Delete(ctx, key)
+}
+
+// Get returns the value for the given key from its shard.
+func (s *ShardedMapStore) Get(ctx context.Context, key string) (string, error) {
+	s.lock.RLock()
+	idx := shardIndex(key, s.shardCount)
+	shard, exist := s.shards[idx]
+	s.lock.RUnlock()
+
+	if !exist {
+		return "", fmt.Errorf("ShardedMapStore %s does not have key %s", s.name, key)
+	}
+	return shard.Get(ctx, key)
+}
+
+// Mutate atomically reads and updates the value for key on its shard. See
+// MapStore.Mutate.
+func (s *ShardedMapStore) Mutate(ctx context.Context, key string, fn func(oldVal string, existed bool) (newVal string, del bool, err error)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	shard, err := s.shardFor(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := shard.Mutate(ctx, key, fn); err != nil {
+		return err
+	}
+
+	return s.maybeSplit(ctx, shard)
+}
+
+// Keys returns every key across all shards.
+func (s *ShardedMapStore) Keys() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	keys := make([]string, 0)
+	for _, shard := range s.shards {
+		for k := range shard.obj.Data {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Range calls fn for every key/value pair across all shards, stopping early
+// if fn returns false.
+func (s *ShardedMapStore) Range(fn func(key, value string) bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, shard := range s.shards {
+		for k, v := range shard.obj.Data {
+			if shard.encode {
+				if raw, err := base64.StdEncoding.DecodeString(v); err == nil {
+					v = string(raw)
+				}
+			}
+			if !fn(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// shardFor returns the MapStore that key currently hashes to, creating it if
+// it doesn't exist yet.
+func (s *ShardedMapStore) shardFor(ctx context.Context, key string) (*MapStore, error) {
+	idx := shardIndex(key, s.shardCount)
+	if shard, exist := s.shards[idx]; exist {
+		return shard, nil
+	}
+
+	shard, err := s.manager.getOrCreateShard(ctx, s.name, idx, s.shardCount)
+	if err != nil {
+		return nil, err
+	}
+	s.shards[idx] = shard
+	return shard, nil
+}
+
+// maybeSplit doubles the shard count and rehashes every key once shard
+// exceeds its byte budget, so no single shard grows past the ConfigMap/Secret
+// size limit. A single key/value pair larger than shardByteBudget can never
+// be relieved this way, since a key is never split across shards; doubling
+// still rehashes it (possibly to a different shard), but that shard remains
+// over budget on its own.
+func (s *ShardedMapStore) maybeSplit(ctx context.Context, shard *MapStore) error {
+	if approxDataSize(shard.obj.Data) < shardByteBudget {
+		return nil
+	}
+
+	newCount := s.shardCount * 2
+	moved := make(map[int]map[string]string)
+
+	for idx, sh := range s.shards {
+		for k, v := range sh.obj.Data {
+			newIdx := shardIndex(k, newCount)
+			if newIdx == idx {
+				continue
+			}
+			if sh.encode {
+				raw, err := base64.StdEncoding.DecodeString(v)
+				if err != nil {
+					return err
+				}
+				v = string(raw)
+			}
+			if moved[newIdx] == nil {
+				moved[newIdx] = make(map[string]string)
+			}
+			moved[newIdx][k] = v
+		}
+	}
+
+	s.shardCount = newCount
+	if len(moved) == 0 {
+		// Every key still hashes to its own shard under newCount (e.g. a
+		// single oversized value with no siblings to redistribute), so
+		// nothing below writes the new count. Persist it on the triggering
+		// shard directly, or a restart's discovery would recover the old,
+		// smaller count and misroute.
+		return s.persistShardCount(ctx, shard, newCount)
+	}
+
+	for newIdx, kv := range moved {
+		dst, ok := s.shards[newIdx]
+		if !ok {
+			var err error
+			dst, err = s.manager.getOrCreateShard(ctx, s.name, newIdx, newCount)
+			if err != nil {
+				return err
+			}
+			s.shards[newIdx] = dst
+		}
+		// Stamp the new shardCount onto every shard this split actually
+		// writes, so a restart's discovery (NewShardedMapStore) recovers
+		// the authoritative N even if the top shard(s) of newCount were
+		// never created (no keys hashed there yet).
+		setShardCountLabel(dst.obj, newCount)
+		for k, v := range kv {
+			if err := dst.Upsert(ctx, k, v); err != nil {
+				return err
+			}
+			oldIdx := indexHolding(s.shards, k, newIdx)
+			if oldIdx >= 0 {
+				setShardCountLabel(s.shards[oldIdx].obj, newCount)
+				if err := s.shards[oldIdx].Delete(ctx, k); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// setShardCountLabel records n as the authoritative shard count on obj's
+// labels, so the next write to obj (Upsert/Delete) persists it.
+func setShardCountLabel(obj *DriverObject, n int) {
+	if obj.Labels == nil {
+		obj.Labels = make(map[string]string, 1)
+	}
+	obj.Labels[shardCountLabelKey] = strconv.Itoa(n)
+}
+
+// persistShardCount stamps count onto shard's labels and writes it
+// immediately, for the case where maybeSplit has nothing to move and so
+// would otherwise never write the new count anywhere.
+func (s *ShardedMapStore) persistShardCount(ctx context.Context, shard *MapStore, count int) error {
+	setShardCountLabel(shard.obj, count)
+	if shard.driver == nil {
+		return nil
+	}
+	ret, err := shard.driver.Update(ctx, shard.namespace, shard.obj)
+	if err != nil {
+		return err
+	}
+	shard.obj = ret
+	return nil
+}
+
+func indexHolding(shards map[int]*MapStore, key string, except int) int {
+	for idx, sh := range shards {
+		if idx == except {
+			continue
+		}
+		if _, ok := sh.obj.Data[key]; ok {
+			return idx
+		}
+	}
+	return -1
+}
+
+func approxDataSize(data map[string]string) int {
+	n := 0
+	for k, v := range data {
+		n += len(k) + len(v)
+	}
+	return n
+}
+
+func shardIndex(key string, n int) int {
+	h := fnv.New32()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+func shardIndexOf(obj *DriverObject) (int, error) {
+	return strconv.Atoi(obj.Labels[shardIndexLabelKey])
+}
+
+// getOrCreateShard returns the shard numbered idx of the logical key space
+// name, creating its backing object on first use. count is the shard count
+// in effect at creation time, recorded on the object so a later restart can
+// recover the authoritative N even from a sparsely-populated shard set.
+func (c *ConfigMapStoreManager) getOrCreateShard(ctx context.Context, name string, idx, count int) (*MapStore, error) {
+	shardName := fmt.Sprintf("%s.%d", name, idx)
+	lbls := map[string]string{
+		shardOfLabelKey:    name,
+		shardIndexLabelKey: strconv.Itoa(idx),
+		shardCountLabelKey: strconv.Itoa(count),
+	}
+
+	obj := &DriverObject{Name: namePrefix + "." + shardName}
+	if c.driver != nil {
+		ret, err := c.driver.Create(ctx, c.defaultNamespace, shardName, lbls)
+		if err != nil {
+			return nil, err
+		}
+		obj = ret
+	}
+	return c.newMapStore(obj), nil
+}
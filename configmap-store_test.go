@@ -1,9 +1,20 @@
 package store
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func TestExtractBaseName(t *testing.T) {
@@ -22,3 +33,380 @@ func TestExtractBaseName(t *testing.T) {
 		assert.Equal(t, test.expect, extractBaseName(test.name))
 	}
 }
+
+func newTestMapStore(name string) *MapStore {
+	return &MapStore{
+		obj:  &DriverObject{Name: namePrefix + "." + name},
+		lock: new(sync.RWMutex),
+	}
+}
+
+func TestTryLockRejectsNonPositiveTTL(t *testing.T) {
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		m := newTestMapStore("foo")
+		err := m.TryLock(context.Background(), "holder", ttl)
+		assert.Error(t, err)
+	}
+}
+
+func TestLockExclusivity(t *testing.T) {
+	m := newTestMapStore("foo")
+
+	assert.NoError(t, m.TryLock(context.Background(), "alice", time.Second))
+	assert.Error(t, m.TryLock(context.Background(), "bob", time.Second))
+	assert.Error(t, m.Unlock(context.Background(), "bob"))
+	assert.NoError(t, m.Unlock(context.Background(), "alice"))
+}
+
+func TestWithSecretsDriverSelectsSecretsDriver(t *testing.T) {
+	o := defaultStoreOptions()
+	WithSecretsDriver()(o)
+
+	d := o.driverFactory(nil)
+	_, ok := d.(*secretsDriver)
+	assert.True(t, ok)
+}
+
+func TestMergeLabels(t *testing.T) {
+	got := mergeLabels(map[string]string{"a": "1"}, map[string]string{"a": "override", "b": "2"})
+	assert.Equal(t, map[string]string{"a": "override", "b": "2"}, got)
+}
+
+// conflictOnceDriver is a Driver that fails the first Update with a 409
+// Conflict and succeeds on every call after, for exercising
+// MapStore.withConflictRetry without a real cluster.
+type conflictOnceDriver struct {
+	obj         *DriverObject
+	updateCalls int
+}
+
+func (d *conflictOnceDriver) Create(ctx context.Context, namespace, name string, lbls map[string]string) (*DriverObject, error) {
+	return d.obj, nil
+}
+
+func (d *conflictOnceDriver) Get(ctx context.Context, namespace, name string) (*DriverObject, error) {
+	return d.obj, nil
+}
+
+func (d *conflictOnceDriver) Update(ctx context.Context, namespace string, obj *DriverObject) (*DriverObject, error) {
+	d.updateCalls++
+	if d.updateCalls == 1 {
+		return nil, apierrs.NewConflict(schema.GroupResource{Resource: "configmaps"}, obj.Name, errors.New("conflict"))
+	}
+	d.obj = obj
+	return obj, nil
+}
+
+func (d *conflictOnceDriver) Delete(ctx context.Context, namespace, name string) error { return nil }
+
+func (d *conflictOnceDriver) List(ctx context.Context, namespace string, selector labels.Selector) ([]*DriverObject, error) {
+	return nil, nil
+}
+
+func (d *conflictOnceDriver) Watch(ctx context.Context, namespace string, selector labels.Selector, onAdd, onUpdate func(*DriverObject), onDelete func(namespace, name string)) error {
+	return nil
+}
+
+func TestMutateRetriesOnConflict(t *testing.T) {
+	obj := &DriverObject{Name: namePrefix + ".foo", Namespace: "default", Data: map[string]string{}}
+	driver := &conflictOnceDriver{obj: obj}
+	m := &MapStore{
+		driver:          driver,
+		namespace:       "default",
+		obj:             obj,
+		lock:            new(sync.RWMutex),
+		conflictRetries: 1,
+		backoff:         time.Millisecond,
+	}
+
+	assert.NoError(t, m.Upsert(context.Background(), "k", "v"))
+	assert.Equal(t, 2, driver.updateCalls)
+
+	val, err := m.Get(context.Background(), "k")
+	assert.NoError(t, err)
+	assert.Equal(t, "v", val)
+}
+
+// failingUpdateDriver is a Driver whose Update always fails with a
+// non-conflict error, for asserting Mutate leaves the in-memory object
+// untouched rather than having already applied the change in place.
+type failingUpdateDriver struct{}
+
+func (d *failingUpdateDriver) Create(ctx context.Context, namespace, name string, lbls map[string]string) (*DriverObject, error) {
+	return nil, nil
+}
+func (d *failingUpdateDriver) Get(ctx context.Context, namespace, name string) (*DriverObject, error) {
+	return nil, nil
+}
+func (d *failingUpdateDriver) Update(ctx context.Context, namespace string, obj *DriverObject) (*DriverObject, error) {
+	return nil, errors.New("boom")
+}
+func (d *failingUpdateDriver) Delete(ctx context.Context, namespace, name string) error { return nil }
+func (d *failingUpdateDriver) List(ctx context.Context, namespace string, selector labels.Selector) ([]*DriverObject, error) {
+	return nil, nil
+}
+func (d *failingUpdateDriver) Watch(ctx context.Context, namespace string, selector labels.Selector, onAdd, onUpdate func(*DriverObject), onDelete func(namespace, name string)) error {
+	return nil
+}
+
+func TestMutateLeavesObjectUntouchedOnNonConflictError(t *testing.T) {
+	obj := &DriverObject{Name: namePrefix + ".foo", Namespace: "default", Data: map[string]string{"k": "v"}}
+	m := &MapStore{
+		driver:    &failingUpdateDriver{},
+		namespace: "default",
+		obj:       obj,
+		lock:      new(sync.RWMutex),
+	}
+
+	assert.Error(t, m.Upsert(context.Background(), "k", "new"))
+	assert.Equal(t, "v", obj.Data["k"])
+}
+
+func TestGetFallsBackToLiveReadOnCacheMiss(t *testing.T) {
+	fresh := &DriverObject{Name: namePrefix + ".foo", Namespace: "default", Data: map[string]string{"k": "fresh"}}
+	driver := &conflictOnceDriver{obj: fresh}
+	m := &MapStore{
+		driver:    driver,
+		namespace: "default",
+		obj:       &DriverObject{Name: namePrefix + ".foo", Namespace: "default", Data: map[string]string{"k": "stale"}},
+		lock:      new(sync.RWMutex),
+		cacheGet:  func(key mapKey) (*DriverObject, bool) { return nil, false },
+	}
+
+	val, err := m.Get(context.Background(), "k")
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", val)
+}
+
+func TestDiffEvents(t *testing.T) {
+	old := &DriverObject{Data: map[string]string{"a": "1", "b": "2"}}
+	updated := &DriverObject{Data: map[string]string{"a": "1", "b": "3", "c": "4"}}
+
+	byKey := make(map[string]Event)
+	for _, ev := range diffEvents("foo", old, updated, false) {
+		byKey[ev.Key] = ev
+	}
+
+	assert.Len(t, byKey, 2)
+	assert.Equal(t, EventUpdated, byKey["b"].Type)
+	assert.Equal(t, EventAdded, byKey["c"].Type)
+}
+
+func TestDiffEventsObjectDeleted(t *testing.T) {
+	old := &DriverObject{Data: map[string]string{"a": "1"}}
+
+	events := diffEvents("foo", old, nil, false)
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, EventDeleted, events[0].Type)
+	assert.Equal(t, "a", events[0].Key)
+}
+
+func TestDiffEventsDecodesEncodedValues(t *testing.T) {
+	old := &DriverObject{Data: map[string]string{"a": base64.StdEncoding.EncodeToString([]byte("1"))}}
+	updated := &DriverObject{Data: map[string]string{"a": base64.StdEncoding.EncodeToString([]byte("2"))}}
+
+	events := diffEvents("foo", old, updated, true)
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, EventUpdated, events[0].Type)
+	assert.Equal(t, "1", events[0].OldValue)
+	assert.Equal(t, "2", events[0].NewValue)
+}
+
+func TestNotAShardSelectorExcludesShards(t *testing.T) {
+	shardLbls := labels.Set{shardOfLabelKey: "foo", shardIndexLabelKey: "0"}
+	baseLbls := labels.Set{namePrefix + "/store": "1"}
+
+	assert.False(t, notAShardSelector.Matches(shardLbls))
+	assert.True(t, notAShardSelector.Matches(baseLbls))
+}
+
+// listSpyDriver is a Driver whose List records the selector it was called
+// with and returns objs, for asserting what a caller queried with.
+type listSpyDriver struct {
+	gotSelector labels.Selector
+	objs        []*DriverObject
+}
+
+func (d *listSpyDriver) Create(ctx context.Context, namespace, name string, lbls map[string]string) (*DriverObject, error) {
+	return nil, nil
+}
+func (d *listSpyDriver) Get(ctx context.Context, namespace, name string) (*DriverObject, error) {
+	return nil, nil
+}
+func (d *listSpyDriver) Update(ctx context.Context, namespace string, obj *DriverObject) (*DriverObject, error) {
+	return obj, nil
+}
+func (d *listSpyDriver) Delete(ctx context.Context, namespace, name string) error { return nil }
+func (d *listSpyDriver) List(ctx context.Context, namespace string, selector labels.Selector) ([]*DriverObject, error) {
+	d.gotSelector = selector
+	return d.objs, nil
+}
+func (d *listSpyDriver) Watch(ctx context.Context, namespace string, selector labels.Selector, onAdd, onUpdate func(*DriverObject), onDelete func(namespace, name string)) error {
+	return nil
+}
+
+func TestQueryExcludesShards(t *testing.T) {
+	shardLbls := labels.Set{shardOfLabelKey: "foo", shardIndexLabelKey: "0"}
+	baseLbls := labels.Set{"store": "1"}
+	driver := &listSpyDriver{}
+	c := &ConfigMapStoreManager{driver: driver, defaultNamespace: "default", lock: new(sync.RWMutex)}
+
+	_, err := c.Query(context.Background(), map[string]string{"store": "1"})
+	assert.NoError(t, err)
+
+	assert.False(t, driver.gotSelector.Matches(shardLbls))
+	assert.True(t, driver.gotSelector.Matches(baseLbls))
+}
+
+func TestMaybeSplitDoesNotDoubleEncodeMovedValues(t *testing.T) {
+	shard0 := &MapStore{
+		obj:    &DriverObject{Name: fmt.Sprintf("%s.foo.0", namePrefix), Data: map[string]string{}},
+		lock:   new(sync.RWMutex),
+		encode: true,
+	}
+	manager := &ConfigMapStoreManager{
+		encodeValues: true,
+		lock:         new(sync.RWMutex),
+		cache:        make(map[mapKey]*DriverObject),
+		cacheLock:    new(sync.RWMutex),
+	}
+	s := &ShardedMapStore{
+		manager:    manager,
+		name:       "foo",
+		lock:       new(sync.RWMutex),
+		shards:     map[int]*MapStore{0: shard0},
+		shardCount: 1,
+	}
+
+	assert.NoError(t, s.Upsert(context.Background(), "k1", "hello world"))
+
+	// Push the shard past its byte budget so the next split moves keys to a
+	// freshly created shard.
+	shard0.obj.Data["filler"] = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", shardByteBudget)))
+
+	assert.NoError(t, s.maybeSplit(context.Background(), shard0))
+	assert.Equal(t, 2, s.shardCount)
+
+	got, err := s.Get(context.Background(), "k1")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", got)
+}
+
+func TestNewShardedMapStoreTrustsPersistedShardCountOverSparseIndices(t *testing.T) {
+	// Only shard 0 was ever written to, but it was created while the
+	// logical key space was split into 4 shards: shards 1-3 simply never
+	// received a key. max(idx)+1 would reconstruct shardCount as 1, which
+	// would then misroute every key hashing to shard 1, 2 or 3.
+	driver := &listSpyDriver{objs: []*DriverObject{
+		{
+			Name:   namePrefix + ".foo.0",
+			Labels: map[string]string{shardOfLabelKey: "foo", shardIndexLabelKey: "0", shardCountLabelKey: "4"},
+			Data:   map[string]string{"k1": "v1"},
+		},
+	}}
+	c := &ConfigMapStoreManager{driver: driver, defaultNamespace: "default", lock: new(sync.RWMutex)}
+
+	s, err := c.NewShardedMapStore(context.Background(), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, s.shardCount)
+}
+
+func TestMaybeSplitPersistsShardCountWhenNothingMoves(t *testing.T) {
+	// "bigkey" hashes to shard 0 both under shardCount 1 and shardCount 2, so
+	// doubling the count relieves nothing: maybeSplit must still persist the
+	// new count on the triggering shard itself.
+	driver := &listSpyDriver{}
+	shard0 := &MapStore{
+		driver: driver,
+		obj: &DriverObject{
+			Name: fmt.Sprintf("%s.foo.0", namePrefix),
+			Data: map[string]string{"bigkey": strings.Repeat("x", shardByteBudget)},
+		},
+		lock: new(sync.RWMutex),
+	}
+	manager := &ConfigMapStoreManager{driver: driver, lock: new(sync.RWMutex)}
+	s := &ShardedMapStore{
+		manager:    manager,
+		name:       "foo",
+		lock:       new(sync.RWMutex),
+		shards:     map[int]*MapStore{0: shard0},
+		shardCount: 1,
+	}
+
+	assert.NoError(t, s.maybeSplit(context.Background(), shard0))
+	assert.Equal(t, 2, s.shardCount)
+	assert.Equal(t, "2", shard0.obj.Labels[shardCountLabelKey])
+}
+
+type testRecord struct {
+	Name string
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec Codec
+	}{
+		{"json", JSONCodec()},
+		{"yaml", YAMLCodec()},
+		{"gob", GobCodec()},
+		{"gzip+json", GzipCodec(JSONCodec())},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := testRecord{Name: "foo"}
+			encoded, err := test.codec.Encode(in)
+			assert.NoError(t, err)
+
+			var out testRecord
+			assert.NoError(t, test.codec.Decode(encoded, &out))
+			assert.Equal(t, in, out)
+		})
+	}
+}
+
+func TestListTypedDecodesEncodedStore(t *testing.T) {
+	store := &MapStore{
+		obj:    &DriverObject{Name: namePrefix + ".foo", Data: map[string]string{}},
+		lock:   new(sync.RWMutex),
+		encode: true,
+	}
+	typed := NewTypedStore[testRecord](store, JSONCodec())
+
+	assert.NoError(t, typed.PutTyped(context.Background(), "k1", testRecord{Name: "a"}))
+	assert.NoError(t, typed.PutTyped(context.Background(), "k2", testRecord{Name: "b"}))
+
+	var got []testRecord
+	assert.NoError(t, typed.ListTyped(context.Background(), &got))
+
+	names := make([]string, 0, len(got))
+	for _, r := range got {
+		names = append(names, r.Name)
+	}
+	assert.ElementsMatch(t, []string{"a", "b"}, names)
+}
+
+func TestListNamespacesAggregatesFromLocalMaps(t *testing.T) {
+	c := &ConfigMapStoreManager{
+		namespaces: []string{metav1.NamespaceAll},
+		localMaps: map[mapKey]string{
+			{Namespace: "ns-a", Name: "foo"}: namePrefix + ".foo",
+			{Namespace: "ns-b", Name: "bar"}: namePrefix + ".bar",
+		},
+		lock: new(sync.RWMutex),
+	}
+
+	assert.ElementsMatch(t, []string{"ns-a", "ns-b"}, c.ListNamespaces())
+}
+
+func TestListNamespacesReturnsConfiguredListWhenNotWatchingAll(t *testing.T) {
+	c := &ConfigMapStoreManager{
+		namespaces: []string{"ns-a", "ns-b"},
+		lock:       new(sync.RWMutex),
+	}
+
+	assert.ElementsMatch(t, []string{"ns-a", "ns-b"}, c.ListNamespaces())
+}
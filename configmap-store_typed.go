@@ -0,0 +1,208 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// Codec serializes Go values to and from the string form stored in a
+// MapStore's Data.
+type Codec interface {
+	Encode(v interface{}) (string, error)
+	Decode(s string, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+// JSONCodec serializes values as JSON.
+func JSONCodec() Codec { return jsonCodec{} }
+
+func (jsonCodec) Encode(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (jsonCodec) Decode(s string, v interface{}) error {
+	return json.Unmarshal([]byte(s), v)
+}
+
+type yamlCodec struct{}
+
+// YAMLCodec serializes values as YAML.
+func YAMLCodec() Codec { return yamlCodec{} }
+
+func (yamlCodec) Encode(v interface{}) (string, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (yamlCodec) Decode(s string, v interface{}) error {
+	return yaml.Unmarshal([]byte(s), v)
+}
+
+type gobCodec struct{}
+
+// GobCodec serializes values with encoding/gob, base64-encoded so the result
+// round-trips safely as ConfigMap/Secret data.
+func GobCodec() Codec { return gobCodec{} }
+
+func (gobCodec) Encode(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func (gobCodec) Decode(s string, v interface{}) error {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(bytes.NewReader(raw)).Decode(v)
+}
+
+type protoCodec struct{}
+
+// ProtoCodec serializes proto.Message values with protobuf, base64-encoded
+// so the result round-trips safely as ConfigMap/Secret data.
+func ProtoCodec() Codec { return protoCodec{} }
+
+func (protoCodec) Encode(v interface{}) (string, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return "", fmt.Errorf("store: %T does not implement proto.Message", v)
+	}
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func (protoCodec) Decode(s string, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("store: %T does not implement proto.Message", v)
+	}
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(raw, msg)
+}
+
+type gzipCodec struct {
+	codec Codec
+}
+
+// GzipCodec wraps codec, gzip-compressing its output and base64-encoding the
+// result so it round-trips safely as ConfigMap/Secret data.
+func GzipCodec(codec Codec) Codec { return gzipCodec{codec: codec} }
+
+func (g gzipCodec) Encode(v interface{}) (string, error) {
+	s, err := g.codec.Encode(v)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func (g gzipCodec) Decode(s string, v interface{}) error {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return g.codec.Decode(string(decoded), v)
+}
+
+// TypedStore serializes Go values of type T into a MapStore's Data via a
+// Codec, making the store usable as a lightweight typed object cache rather
+// than only a string-to-string map.
+type TypedStore[T any] struct {
+	store *MapStore
+	codec Codec
+}
+
+// NewTypedStore wraps store with codec to read and write values of type T.
+func NewTypedStore[T any](store *MapStore, codec Codec) *TypedStore[T] {
+	return &TypedStore[T]{store: store, codec: codec}
+}
+
+// PutTyped serializes v with the codec and upserts it under key.
+func (t *TypedStore[T]) PutTyped(ctx context.Context, key string, v T) error {
+	encoded, err := t.codec.Encode(v)
+	if err != nil {
+		return err
+	}
+	return t.store.Upsert(ctx, key, encoded)
+}
+
+// GetTyped fetches the value under key and deserializes it into v.
+func (t *TypedStore[T]) GetTyped(ctx context.Context, key string, v *T) error {
+	encoded, err := t.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return t.codec.Decode(encoded, v)
+}
+
+// ListTyped deserializes every value currently in the store, appending one
+// element per key to into.
+func (t *TypedStore[T]) ListTyped(ctx context.Context, into *[]T) error {
+	if t.store.driver != nil {
+		obj, err := t.store.driver.Get(ctx, t.store.namespace, t.store.obj.Name)
+		if err != nil {
+			return err
+		}
+		t.store.obj = obj
+	}
+
+	for _, raw := range t.store.obj.Data {
+		decoded, err := t.store.decode(raw)
+		if err != nil {
+			return err
+		}
+		var v T
+		if err := t.codec.Decode(decoded, &v); err != nil {
+			return err
+		}
+		*into = append(*into, v)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+)
+
+// getOptions configures a single Get/GetMapStore call.
+type getOptions struct {
+	live bool
+}
+
+// GetOption configures a single Get/GetMapStore call.
+type GetOption func(*getOptions)
+
+// WithLiveRead forces Get/GetMapStore to bypass the informer-backed cache
+// and read the backing object directly from the API server.
+func WithLiveRead() GetOption {
+	return func(o *getOptions) { o.live = true }
+}
+
+// EventType identifies the kind of change an Event describes.
+type EventType string
+
+const (
+	// EventAdded is emitted the first time a key is observed.
+	EventAdded EventType = "Added"
+	// EventUpdated is emitted when a key's value changes.
+	EventUpdated EventType = "Updated"
+	// EventDeleted is emitted when a key is removed, or its MapStore is deleted.
+	EventDeleted EventType = "Deleted"
+)
+
+// Event describes an add/update/delete observed on a single key of a
+// watched MapStore's Data.
+type Event struct {
+	Type     EventType
+	Name     string
+	Key      string
+	OldValue string
+	NewValue string
+}
+
+// Watch returns a channel of Events describing changes to the named
+// MapStore's Data in namespace, observed through the manager's informer.
+// Pass "" for namespace to use the manager's default namespace. The channel
+// is closed once ctx is done.
+func (c *ConfigMapStoreManager) Watch(ctx context.Context, namespace, name string) (<-chan Event, error) {
+	if namespace == "" {
+		namespace = c.defaultNamespace
+	}
+	key := mapKey{Namespace: namespace, Name: name}
+	ch := make(chan Event, 16)
+
+	c.watchersLock.Lock()
+	c.watchers[key] = append(c.watchers[key], ch)
+	c.watchersLock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		c.watchersLock.Lock()
+		defer c.watchersLock.Unlock()
+		chans := c.watchers[key]
+		for i, existing := range chans {
+			if existing == ch {
+				c.watchers[key] = append(chans[:i], chans[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+func (c *ConfigMapStoreManager) onAdd(obj *DriverObject) {
+	key := mapKey{Namespace: obj.Namespace, Name: extractBaseName(obj.Name)}
+
+	c.lock.Lock()
+	c.localMaps[key] = obj.Name
+	c.lock.Unlock()
+
+	old, _ := c.cacheLookup(key)
+	c.cacheStore(key, obj)
+	c.dispatch(key, diffEvents(key.Name, old, obj, c.encodeValues))
+}
+
+func (c *ConfigMapStoreManager) onUpdate(obj *DriverObject) {
+	key := mapKey{Namespace: obj.Namespace, Name: extractBaseName(obj.Name)}
+
+	old, _ := c.cacheLookup(key)
+	c.cacheStore(key, obj)
+	c.dispatch(key, diffEvents(key.Name, old, obj, c.encodeValues))
+}
+
+func (c *ConfigMapStoreManager) onDelete(namespace, fullName string) {
+	key := mapKey{Namespace: namespace, Name: extractBaseName(fullName)}
+
+	c.lock.Lock()
+	delete(c.localMaps, key)
+	c.lock.Unlock()
+
+	old, ok := c.cacheLookup(key)
+	c.cacheDelete(key)
+	if !ok {
+		return
+	}
+	c.dispatch(key, diffEvents(key.Name, old, nil, c.encodeValues))
+}
+
+// diffEvents compares old and new Data key by key, returning one Event per
+// added, changed, or removed key. A nil old or new means the object did not
+// exist before/after the change, i.e. every key was added or removed.
+// OldValue/NewValue are decoded the same way Get decodes them, so Watch
+// consumers see the same plaintext surface as Get regardless of whether the
+// manager was built with WithEncodedValues(); a value that fails to decode
+// is passed through raw.
+func diffEvents(name string, old, new *DriverObject, encode bool) []Event {
+	var oldData, newData map[string]string
+	if old != nil {
+		oldData = old.Data
+	}
+	if new != nil {
+		newData = new.Data
+	}
+
+	var events []Event
+	for k, v := range newData {
+		v := decodeEventValue(v, encode)
+		if ov, existed := oldData[k]; existed {
+			ov := decodeEventValue(ov, encode)
+			if ov != v {
+				events = append(events, Event{Type: EventUpdated, Name: name, Key: k, OldValue: ov, NewValue: v})
+			}
+		} else {
+			events = append(events, Event{Type: EventAdded, Name: name, Key: k, NewValue: v})
+		}
+	}
+	for k, v := range oldData {
+		if _, exist := newData[k]; !exist {
+			events = append(events, Event{Type: EventDeleted, Name: name, Key: k, OldValue: decodeEventValue(v, encode)})
+		}
+	}
+	return events
+}
+
+// decodeEventValue decodes val for inclusion in an Event, leaving it as-is if
+// encode is false or val turns out not to be valid base64.
+func decodeEventValue(val string, encode bool) string {
+	decoded, err := decodeValue(val, encode)
+	if err != nil {
+		return val
+	}
+	return decoded
+}
+
+func (c *ConfigMapStoreManager) dispatch(key mapKey, events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	c.watchersLock.Lock()
+	defer c.watchersLock.Unlock()
+
+	for _, ev := range events {
+		for _, ch := range c.watchers[key] {
+			select {
+			case ch <- ev:
+			default:
+			}
+		}
+	}
+}
+
+func (c *ConfigMapStoreManager) cacheLookup(key mapKey) (*DriverObject, bool) {
+	c.cacheLock.RLock()
+	defer c.cacheLock.RUnlock()
+	obj, ok := c.cache[key]
+	return obj, ok
+}
+
+func (c *ConfigMapStoreManager) cacheStore(key mapKey, obj *DriverObject) {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+	c.cache[key] = obj
+}
+
+func (c *ConfigMapStoreManager) cacheDelete(key mapKey) {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+	delete(c.cache, key)
+}